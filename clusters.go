@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// nomadServerFlags implements flag.Value so --nomad.server can be repeated
+// to scrape multiple Nomad regions/deployments from one exporter process.
+type nomadServerFlags []string
+
+func (f *nomadServerFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *nomadServerFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// clusterTLSConfig mirrors the --tls.* flags for a single cluster entry in
+// the YAML config file.
+type clusterTLSConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CAPath     string `yaml:"ca_path"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	Insecure   bool   `yaml:"insecure"`
+	ServerName string `yaml:"server_name"`
+}
+
+// clusterConfig describes one Nomad region/deployment to scrape. The
+// exporter builds one Exporter per clusterConfig and labels every metric it
+// produces with "cluster".
+type clusterConfig struct {
+	Name       string           `yaml:"name"`
+	Address    string           `yaml:"address"`
+	Region     string           `yaml:"region"`
+	Token      string           `yaml:"token"`
+	TokenFile  string           `yaml:"token_file"`
+	AuthMethod string           `yaml:"auth_method"`
+	Namespace  string           `yaml:"namespace"`
+	TLS        clusterTLSConfig `yaml:"tls"`
+}
+
+// clustersFile is the top-level shape of --config.file.
+type clustersFile struct {
+	Clusters []clusterConfig `yaml:"clusters"`
+}
+
+// loadClustersFile parses a YAML file of the form:
+//
+//	clusters:
+//	  - name: us-east
+//	    address: https://nomad-us-east:4646
+//	    region: us-east
+func loadClustersFile(path string) ([]clusterConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f clustersFile
+	if err := yaml.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	return f.Clusters, nil
+}
+
+// clustersFromServers builds one clusterConfig per --nomad.server flag,
+// deriving a cluster name from the server address since no YAML config was
+// given.
+func clustersFromServers(servers []string) []clusterConfig {
+	clusters := make([]clusterConfig, 0, len(servers))
+	for _, addr := range servers {
+		clusters = append(clusters, clusterConfig{
+			Name:    clusterNameFromAddress(addr),
+			Address: addr,
+		})
+	}
+	return clusters
+}
+
+// firstNonEmpty returns the first non-empty string, used to let a
+// per-cluster YAML TLS setting override the shared --tls.* flag defaults.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clusterNameFromAddress derives a readable default cluster label from a
+// Nomad HTTP API address, e.g. "https://nomad.example.com:4646" ->
+// "nomad.example.com:4646". The port is kept (when the address specifies
+// one) so that multiple local/federated clusters on the same host but
+// different ports, e.g. "http://localhost:4646" and "http://localhost:4647",
+// don't default to the same cluster name.
+func clusterNameFromAddress(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil || u.Hostname() == "" {
+		return addr
+	}
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+	return host
+}
+
+// validateClusterNames rejects an empty or duplicate cluster name, either of
+// which would make two clusters share the same "cluster" label value and
+// panic prometheus.MustRegister with "duplicate metrics collector
+// registration attempted" at startup instead of failing with a clear error.
+func validateClusterNames(clusters []clusterConfig) error {
+	seen := make(map[string]bool, len(clusters))
+	for _, cl := range clusters {
+		if cl.Name == "" {
+			return fmt.Errorf("cluster with address %q has no name", cl.Address)
+		}
+		if seen[cl.Name] {
+			return fmt.Errorf("duplicate cluster name %q", cl.Name)
+		}
+		seen[cl.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pere3/nomad-exporter/internal/poller"
+)
+
+var (
+	scrapeCollectorDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
+		"Time it took to run a Nomad sub-collector.",
+		[]string{"collector"}, nil,
+	)
+	scrapeCollectorSuccess = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+		"Whether a Nomad sub-collector succeeded.",
+		[]string{"collector"}, nil,
+	)
+)
+
+// dataSource is what a collector reads from: client for the handful of
+// endpoints that are cheap/low-cardinality enough to call inline
+// (deployments, server status), and the poller's snapshot for everything
+// the background poller keeps warm (allocations, nodes, jobs, evaluations).
+type dataSource struct {
+	client    *api.Client
+	poller    *poller.Poller
+	snapshot  *poller.Snapshot
+	token     string
+	namespace string
+}
+
+// queryOptions builds the api.QueryOptions a collector should use for an
+// inline (non-poller) call, carrying the exporter's configured ACL token
+// and namespace.
+func (ds *dataSource) queryOptions() *api.QueryOptions {
+	return &api.QueryOptions{AuthToken: ds.token, Namespace: ds.namespace}
+}
+
+// collector is implemented by each of the per-resource sub-collectors
+// (jobCollector, nodeCollector, ...) that make up an Exporter. Each
+// collector owns a disjoint slice of the Nomad metric surface so it can be
+// toggled independently via --collector.<name>.
+type collector interface {
+	// name identifies the collector for the --collector.<name> flag and the
+	// collector label on nomad_scrape_collector_duration_seconds/success.
+	name() string
+	// describe sends the Descs this collector can emit.
+	describe(ch chan<- *prometheus.Desc)
+	// collect emits metrics from ds. A non-nil error marks the collector as
+	// failed for this scrape but does not abort the others.
+	collect(ds *dataSource, ch chan<- prometheus.Metric) error
+}
+
+// defaultCollectors returns every known sub-collector keyed by name. Whether
+// each one is enabled by default is decided by defaultCollectorEnabled.
+func defaultCollectors() map[string]collector {
+	return map[string]collector{
+		"alloc":       &allocCollector{},
+		"job":         &jobCollector{},
+		"node":        &nodeCollector{},
+		"deployment":  &deploymentCollector{},
+		"eval":        &evalCollector{},
+		"server":      &serverCollector{},
+		"cache":       &cacheCollector{},
+		"alloc-stats": &allocStatsCollector{},
+	}
+}
+
+// defaultCollectorEnabled reports whether the named collector is on by
+// default. New, more expensive collectors default to off so enabling the
+// exporter doesn't silently increase load on Nomad.
+func defaultCollectorEnabled(name string) bool {
+	switch name {
+	case "alloc", "node", "cache":
+		return true
+	default:
+		return false
+	}
+}
+
+// runCollector times c.collect and reports its outcome via the
+// nomad_scrape_collector_duration_seconds/success metric pair.
+func runCollector(c collector, ds *dataSource, ch chan<- prometheus.Metric) {
+	start := time.Now()
+	err := c.collect(ds, ch)
+	duration := time.Since(start).Seconds()
+
+	success := 1.0
+	if err != nil {
+		success = 0
+		log.Println("collector", c.name(), "scrape failed:", err)
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeCollectorDuration, prometheus.GaugeValue, duration, c.name())
+	ch <- prometheus.MustNewConstMetric(scrapeCollectorSuccess, prometheus.GaugeValue, success, c.name())
+}
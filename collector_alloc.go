@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	allocationMemoryLimit = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_memory_limit"),
+		"Allocation memory limit",
+		[]string{"job", "group", "alloc", "alloc_id", "region", "datacenter", "node", "namespace"}, nil,
+	)
+	allocationCPULimit = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_cpu_limit"),
+		"Allocation CPU limit in MHz",
+		[]string{"job", "group", "alloc", "alloc_id", "region", "datacenter", "node", "namespace"}, nil,
+	)
+	allocationDiskLimit = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_disk_limit"),
+		"Allocation disk limit in MB",
+		[]string{"job", "group", "alloc", "alloc_id", "region", "datacenter", "node", "namespace"}, nil,
+	)
+	allocationRestartCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_restart_count"),
+		"Number of times tasks in this allocation have restarted",
+		[]string{"job", "group", "alloc", "alloc_id", "region", "datacenter", "node", "namespace"}, nil,
+	)
+)
+
+// allocCollector exposes per-allocation resource limits and restart counts
+// for running allocations, served from the poller's snapshot.
+type allocCollector struct{}
+
+func (c *allocCollector) name() string { return "alloc" }
+
+func (c *allocCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- allocationMemoryLimit
+	ch <- allocationCPULimit
+	ch <- allocationDiskLimit
+	ch <- allocationRestartCount
+}
+
+func (c *allocCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	for _, alloc := range ds.snapshot.Allocations {
+		node := ds.snapshot.Nodes[alloc.NodeID]
+		if node == nil {
+			continue
+		}
+		if alloc.Job == nil || alloc.Job.Name == nil || alloc.Job.Region == nil || alloc.Resources == nil {
+			continue
+		}
+
+		labels := []string{*alloc.Job.Name, alloc.TaskGroup, alloc.Name, alloc.ID, *alloc.Job.Region, node.Datacenter, node.Name, alloc.Namespace}
+
+		ch <- prometheus.MustNewConstMetric(
+			allocationMemoryLimit, prometheus.GaugeValue, float64(intVal(alloc.Resources.MemoryMB)), labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			allocationCPULimit, prometheus.GaugeValue, float64(intVal(alloc.Resources.CPU)), labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			allocationDiskLimit, prometheus.GaugeValue, float64(intVal(alloc.Resources.DiskMB)), labels...,
+		)
+
+		var restarts int
+		for _, ts := range alloc.TaskStates {
+			restarts += int(ts.Restarts)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			allocationRestartCount, prometheus.GaugeValue, float64(restarts), labels...,
+		)
+	}
+	return nil
+}
+
+// intVal returns *p, or 0 if p is nil.
+func intVal(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
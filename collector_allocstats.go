@@ -0,0 +1,102 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// allocStatsConfig holds the --collector.alloc-stats.* flag values.
+type allocStatsConfig struct {
+	interval    time.Duration
+	concurrency int
+}
+
+var (
+	allocationCPUTicks = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_cpu_ticks"),
+		"Allocation CPU usage in ticks",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationMemoryRSSBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_memory_rss_bytes"),
+		"Allocation resident set size memory usage in bytes",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationMemoryCacheBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_memory_cache_bytes"),
+		"Allocation page cache memory usage in bytes",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationMemorySwapBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_memory_swap_bytes"),
+		"Allocation swap memory usage in bytes",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationTaskRestartsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_task_restarts_total"),
+		"Number of times a task within an allocation has restarted",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationOOMKillsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_oom_kills_total"),
+		"Number of times a task within an allocation has been OOM killed",
+		[]string{"job", "group", "alloc_id", "task", "node"}, nil,
+	)
+	allocationStatsScrapeErrorsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "allocation_stats_scrape_errors_total"),
+		"Number of failed alloc stats requests to a node's client agent",
+		[]string{"node"}, nil,
+	)
+)
+
+// allocStatsCollector exposes real CPU/memory usage per running allocation,
+// as opposed to the static limits allocCollector reports. It is expensive
+// (one Stats request per allocation, sent to each node's client agent
+// directly) so it is off by default and refreshed on its own
+// --collector.alloc-stats.interval by poller.Poller.RunStats, independent
+// of scrape interval.
+type allocStatsCollector struct{}
+
+func (c *allocStatsCollector) name() string { return "alloc-stats" }
+
+func (c *allocStatsCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- allocationCPUTicks
+	ch <- allocationMemoryRSSBytes
+	ch <- allocationMemoryCacheBytes
+	ch <- allocationMemorySwapBytes
+	ch <- allocationTaskRestartsTotal
+	ch <- allocationOOMKillsTotal
+	ch <- allocationStatsScrapeErrorsTotal
+}
+
+func (c *allocStatsCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	for id, stats := range ds.poller.StatsSnapshot() {
+		alloc, ok := ds.snapshot.Allocations[id]
+		if !ok {
+			continue
+		}
+		node := ds.snapshot.Nodes[alloc.NodeID]
+		if node == nil {
+			continue
+		}
+		if alloc.Job == nil || alloc.Job.Name == nil {
+			continue
+		}
+
+		for task, ts := range stats.Tasks {
+			labels := []string{*alloc.Job.Name, alloc.TaskGroup, alloc.ID, task, node.Name}
+			ch <- prometheus.MustNewConstMetric(allocationCPUTicks, prometheus.GaugeValue, ts.CPUTicks, labels...)
+			ch <- prometheus.MustNewConstMetric(allocationMemoryRSSBytes, prometheus.GaugeValue, float64(ts.MemoryRSS), labels...)
+			ch <- prometheus.MustNewConstMetric(allocationMemoryCacheBytes, prometheus.GaugeValue, float64(ts.MemoryCache), labels...)
+			ch <- prometheus.MustNewConstMetric(allocationMemorySwapBytes, prometheus.GaugeValue, float64(ts.MemorySwap), labels...)
+			ch <- prometheus.MustNewConstMetric(allocationTaskRestartsTotal, prometheus.CounterValue, float64(ts.Restarts), labels...)
+			ch <- prometheus.MustNewConstMetric(allocationOOMKillsTotal, prometheus.CounterValue, float64(ts.OOMKills), labels...)
+		}
+	}
+
+	for node, count := range ds.poller.StatsErrorCounts() {
+		ch <- prometheus.MustNewConstMetric(allocationStatsScrapeErrorsTotal, prometheus.CounterValue, count, node)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheLastUpdate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "last_update_timestamp_seconds"),
+		"Unix timestamp of the last successful background poller refresh.",
+		nil, nil,
+	)
+	apiRequestsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "api", "requests_total"),
+		"Number of requests the background poller has made to the Nomad API.",
+		[]string{"endpoint", "code"}, nil,
+	)
+)
+
+// cacheCollector exposes observability into the background poller itself,
+// rather than Nomad cluster state.
+type cacheCollector struct{}
+
+func (c *cacheCollector) name() string { return "cache" }
+
+func (c *cacheCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheLastUpdate
+	ch <- apiRequestsTotal
+}
+
+func (c *cacheCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(
+		cacheLastUpdate, prometheus.GaugeValue, float64(ds.snapshot.UpdatedAt.Unix()),
+	)
+	for _, rc := range ds.poller.RequestCounts() {
+		ch <- prometheus.MustNewConstMetric(apiRequestsTotal, prometheus.CounterValue, rc.Count, rc.Endpoint, rc.Code)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	deploymentDesired = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "deployment", "task_group_desired"),
+		"Desired allocation count for a deployment's task group",
+		[]string{"deployment_id", "job", "group"}, nil,
+	)
+	deploymentHealthy = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "deployment", "task_group_healthy"),
+		"Healthy allocation count for a deployment's task group",
+		[]string{"deployment_id", "job", "group"}, nil,
+	)
+	deploymentUnhealthy = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "deployment", "task_group_unhealthy"),
+		"Unhealthy allocation count for a deployment's task group",
+		[]string{"deployment_id", "job", "group"}, nil,
+	)
+)
+
+// deploymentCollector exposes in-flight deployment progress per task group.
+type deploymentCollector struct{}
+
+func (c *deploymentCollector) name() string { return "deployment" }
+
+func (c *deploymentCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- deploymentDesired
+	ch <- deploymentHealthy
+	ch <- deploymentUnhealthy
+}
+
+func (c *deploymentCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	deployments, _, err := ds.client.Deployments().List(ds.queryOptions())
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deployments {
+		if d.Status != "running" {
+			continue
+		}
+		for group, state := range d.TaskGroups {
+			labels := []string{d.ID, d.JobID, group}
+			ch <- prometheus.MustNewConstMetric(deploymentDesired, prometheus.GaugeValue, float64(state.DesiredTotal), labels...)
+			ch <- prometheus.MustNewConstMetric(deploymentHealthy, prometheus.GaugeValue, float64(state.HealthyAllocs), labels...)
+			ch <- prometheus.MustNewConstMetric(deploymentUnhealthy, prometheus.GaugeValue, float64(state.UnhealthyAllocs), labels...)
+		}
+	}
+	return nil
+}
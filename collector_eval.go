@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	evalPending = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "eval", "pending"),
+		"Number of pending evaluations",
+		nil, nil,
+	)
+	evalBlocked = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "eval", "blocked"),
+		"Number of blocked evaluations",
+		nil, nil,
+	)
+)
+
+// evalCollector exposes the count of evaluations waiting in the scheduler's
+// pending and blocked states, served from the poller's snapshot.
+type evalCollector struct{}
+
+func (c *evalCollector) name() string { return "eval" }
+
+func (c *evalCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- evalPending
+	ch <- evalBlocked
+}
+
+func (c *evalCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	var pending, blocked float64
+	for _, e := range ds.snapshot.Evaluations {
+		switch e.Status {
+		case "pending":
+			pending++
+		case "blocked":
+			blocked++
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(evalPending, prometheus.GaugeValue, pending)
+	ch <- prometheus.MustNewConstMetric(evalBlocked, prometheus.GaugeValue, blocked)
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobTaskGroupDesired = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "job", "task_group_desired"),
+		"Desired count of a job's task group",
+		[]string{"job", "group", "namespace"}, nil,
+	)
+	jobTaskGroupRunning = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "job", "task_group_running"),
+		"Running count of a job's task group",
+		[]string{"job", "group", "namespace"}, nil,
+	)
+	jobTaskGroupStarting = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "job", "task_group_starting"),
+		"Starting count of a job's task group",
+		[]string{"job", "group", "namespace"}, nil,
+	)
+	jobTaskGroupFailed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "job", "task_group_failed"),
+		"Failed count of a job's task group",
+		[]string{"job", "group", "namespace"}, nil,
+	)
+	jobTaskGroupLost = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "job", "task_group_lost"),
+		"Lost count of a job's task group",
+		[]string{"job", "group", "namespace"}, nil,
+	)
+)
+
+// jobCollector exposes per-job, per-task-group placement counts, served
+// from the poller's snapshot.
+type jobCollector struct{}
+
+func (c *jobCollector) name() string { return "job" }
+
+func (c *jobCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- jobTaskGroupDesired
+	ch <- jobTaskGroupRunning
+	ch <- jobTaskGroupStarting
+	ch <- jobTaskGroupFailed
+	ch <- jobTaskGroupLost
+}
+
+func (c *jobCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	for id, job := range ds.snapshot.Jobs {
+		summary, ok := ds.snapshot.JobSummaries[id]
+		if !ok {
+			continue
+		}
+		if job.Name == nil || job.Namespace == nil {
+			continue
+		}
+
+		desired := make(map[string]int, len(job.TaskGroups))
+		for _, tg := range job.TaskGroups {
+			if tg.Name != nil {
+				desired[*tg.Name] = intVal(tg.Count)
+			}
+		}
+
+		for group, s := range summary.Summary {
+			labels := []string{*job.Name, group, *job.Namespace}
+			ch <- prometheus.MustNewConstMetric(jobTaskGroupDesired, prometheus.GaugeValue, float64(desired[group]), labels...)
+			ch <- prometheus.MustNewConstMetric(jobTaskGroupRunning, prometheus.GaugeValue, float64(s.Running), labels...)
+			ch <- prometheus.MustNewConstMetric(jobTaskGroupStarting, prometheus.GaugeValue, float64(s.Starting), labels...)
+			ch <- prometheus.MustNewConstMetric(jobTaskGroupFailed, prometheus.GaugeValue, float64(s.Failed), labels...)
+			ch <- prometheus.MustNewConstMetric(jobTaskGroupLost, prometheus.GaugeValue, float64(s.Lost), labels...)
+		}
+	}
+	return nil
+}
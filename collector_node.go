@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	nodeInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "info"),
+		"Labeled Nomad node information, always 1",
+		[]string{"node", "datacenter", "node_class", "status", "scheduling_eligibility", "drain"}, nil,
+	)
+	nodeResourceCPU = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "resource_cpu_mhz"),
+		"Total CPU in MHz on the node",
+		[]string{"node", "datacenter"}, nil,
+	)
+	nodeResourceMemory = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "resource_memory_bytes"),
+		"Total memory in bytes on the node",
+		[]string{"node", "datacenter"}, nil,
+	)
+	nodeResourceDisk = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "resource_disk_bytes"),
+		"Total disk in bytes on the node",
+		[]string{"node", "datacenter"}, nil,
+	)
+)
+
+// nodeCollector exposes node status, eligibility, drain state and resource
+// totals, served from the poller's snapshot.
+type nodeCollector struct{}
+
+func (c *nodeCollector) name() string { return "node" }
+
+func (c *nodeCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeInfo
+	ch <- nodeResourceCPU
+	ch <- nodeResourceMemory
+	ch <- nodeResourceDisk
+}
+
+func (c *nodeCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	for _, n := range ds.snapshot.Nodes {
+		ch <- prometheus.MustNewConstMetric(
+			nodeInfo, prometheus.GaugeValue, 1,
+			n.Name, n.Datacenter, n.NodeClass, n.Status, n.SchedulingEligibility, boolLabel(n.Drain),
+		)
+
+		if n.NodeResources == nil {
+			continue
+		}
+		if cpu := n.NodeResources.Cpu; cpu.CpuShares != 0 {
+			ch <- prometheus.MustNewConstMetric(nodeResourceCPU, prometheus.GaugeValue, float64(cpu.CpuShares), n.Name, n.Datacenter)
+		}
+		if mem := n.NodeResources.Memory; mem.MemoryMB != 0 {
+			ch <- prometheus.MustNewConstMetric(nodeResourceMemory, prometheus.GaugeValue, float64(mem.MemoryMB)*1024*1024, n.Name, n.Datacenter)
+		}
+		if disk := n.NodeResources.Disk; disk.DiskMB != 0 {
+			ch <- prometheus.MustNewConstMetric(nodeResourceDisk, prometheus.GaugeValue, float64(disk.DiskMB)*1024*1024, n.Name, n.Datacenter)
+		}
+	}
+	return nil
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	serverPeers = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "server", "peers"),
+		"Number of Nomad server peers known to the queried agent",
+		nil, nil,
+	)
+	serverLeader = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "server", "leader"),
+		"Labeled with the current Raft leader address, always 1",
+		[]string{"leader"}, nil,
+	)
+)
+
+// serverCollector exposes leader/peer state from the Nomad server's
+// /v1/status endpoints.
+type serverCollector struct{}
+
+func (c *serverCollector) name() string { return "server" }
+
+func (c *serverCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- serverPeers
+	ch <- serverLeader
+}
+
+func (c *serverCollector) collect(ds *dataSource, ch chan<- prometheus.Metric) error {
+	peers, err := ds.client.Status().Peers()
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(serverPeers, prometheus.GaugeValue, float64(len(peers)))
+
+	leader, err := ds.client.Status().Leader()
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(serverLeader, prometheus.GaugeValue, 1, leader)
+	return nil
+}
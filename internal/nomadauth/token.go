@@ -0,0 +1,170 @@
+// Package nomadauth resolves the Nomad ACL SecretID the exporter should
+// present on each request: a static token, a token file re-read on
+// rotation, or a Nomad workload-identity JWT exchanged at /v1/acl/login and
+// cached until it nears expiry.
+package nomadauth
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// expiryMargin is how far ahead of a workload-identity token's expiration
+// the manager proactively re-exchanges it.
+const expiryMargin = 30 * time.Second
+
+// Config describes how to obtain a SecretID.
+//
+//   - StaticToken alone: used as-is for the life of the process.
+//   - TokenFile, AuthMethod empty: TokenFile's contents are the SecretID,
+//     re-read whenever the file changes on disk.
+//   - TokenFile and AuthMethod: TokenFile's contents are a Nomad workload
+//     identity JWT, exchanged via ACLAuth().Login(AuthMethod) for a SecretID
+//     that is cached until it's close to expiring.
+type Config struct {
+	Client      *api.Client
+	StaticToken string
+	TokenFile   string
+	AuthMethod  string
+
+	// ReloadInterval controls how often TokenFile is checked for changes.
+	// Defaults to 30s when zero.
+	ReloadInterval time.Duration
+}
+
+// ResolveDefaults fills in token/tokenFile from the NOMAD_SECRET_ID and
+// NOMAD_TOKEN_FILE environment variables when the corresponding flag value
+// is empty, matching what a Nomad task with workload identity sets.
+func ResolveDefaults(token, tokenFile string) (resolvedToken, resolvedTokenFile string) {
+	if token == "" {
+		token = os.Getenv("NOMAD_SECRET_ID")
+	}
+	if tokenFile == "" {
+		tokenFile = os.Getenv("NOMAD_TOKEN_FILE")
+	}
+	return token, tokenFile
+}
+
+// Manager serves the current SecretID, transparently handling file
+// rotation and workload-identity token exchange/renewal in the background.
+type Manager struct {
+	cfg Config
+
+	mu          sync.Mutex
+	current     string
+	expiresAt   time.Time
+	fileModTime time.Time
+}
+
+// NewManager builds a Manager and, for file- or workload-identity-backed
+// tokens, performs the initial load before returning.
+func NewManager(cfg Config) (*Manager, error) {
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = 30 * time.Second
+	}
+	m := &Manager{cfg: cfg}
+
+	if cfg.StaticToken != "" {
+		m.current = cfg.StaticToken
+		return m, nil
+	}
+	if cfg.TokenFile == "" {
+		return m, nil
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if cfg.AuthMethod == "" {
+		go m.watchFile()
+	}
+	return m, nil
+}
+
+// SecretID returns the token to present on the next Nomad request,
+// transparently renewing a workload-identity token that's about to expire.
+func (m *Manager) SecretID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cfg.AuthMethod != "" && time.Now().After(m.expiresAt.Add(-expiryMargin)) {
+		if err := m.reloadLocked(); err != nil {
+			log.Println("nomadauth: renewing workload identity token failed, reusing last-known token:", err)
+		}
+	}
+	return m.current
+}
+
+func (m *Manager) watchFile() {
+	t := time.NewTicker(m.cfg.ReloadInterval)
+	defer t.Stop()
+	for range t.C {
+		if err := m.reloadIfChanged(); err != nil {
+			log.Println("nomadauth: reloading token file failed, keeping previous token:", err)
+		}
+	}
+}
+
+func (m *Manager) reloadIfChanged() error {
+	fi, err := os.Stat(m.cfg.TokenFile)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	unchanged := fi.ModTime().Equal(m.fileModTime)
+	m.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reloadLocked()
+}
+
+func (m *Manager) reload() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reloadLocked()
+}
+
+// reloadLocked re-reads cfg.TokenFile and, for the workload-identity case,
+// exchanges its contents for a fresh SecretID. Callers must hold m.mu.
+func (m *Manager) reloadLocked() error {
+	raw, err := ioutil.ReadFile(m.cfg.TokenFile)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(m.cfg.TokenFile)
+	if err != nil {
+		return err
+	}
+	contents := strings.TrimSpace(string(raw))
+
+	if m.cfg.AuthMethod == "" {
+		m.current = contents
+		m.fileModTime = fi.ModTime()
+		return nil
+	}
+
+	token, _, err := m.cfg.Client.ACLAuth().Login(&api.ACLLoginRequest{
+		AuthMethodName: m.cfg.AuthMethod,
+		LoginToken:     contents,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	m.current = token.SecretID
+	m.fileModTime = fi.ModTime()
+	if token.ExpirationTime != nil {
+		m.expiresAt = *token.ExpirationTime
+	} else {
+		m.expiresAt = time.Now().Add(time.Hour)
+	}
+	return nil
+}
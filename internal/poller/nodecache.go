@@ -0,0 +1,83 @@
+package poller
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// defaultNodeCacheCapacity bounds how many full api.Node records pollNodes
+// keeps around. It only needs to cover the cluster's live node count, but a
+// generous cap means a cluster that cycles through many short-lived nodes
+// (autoscaled client pools) doesn't grow the cache without bound.
+const defaultNodeCacheCapacity = 4096
+
+// nodeCache is an LRU cache of full api.Node records keyed by node ID, used
+// to avoid an Info call for nodes whose ModifyIndex hasn't changed since the
+// last poll. Safe for concurrent use.
+type nodeCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List // of *nodeCacheEntry, most-recently-used at the front
+	elems    map[string]*list.Element
+}
+
+type nodeCacheEntry struct {
+	id          string
+	node        *api.Node
+	modifyIndex uint64
+}
+
+func newNodeCache(capacity int) *nodeCache {
+	if capacity <= 0 {
+		capacity = defaultNodeCacheCapacity
+	}
+	return &nodeCache{
+		capacity: capacity,
+		ll:       list.New(),
+		elems:    map[string]*list.Element{},
+	}
+}
+
+// get returns the cached node for id if present and still current as of
+// modifyIndex, promoting it to most-recently-used.
+func (c *nodeCache) get(id string, modifyIndex uint64) (*api.Node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.elems[id]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*nodeCacheEntry)
+	if entry.modifyIndex != modifyIndex {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.node, true
+}
+
+// put inserts or refreshes id's cache entry, evicting the least-recently-used
+// entry if the cache is over capacity.
+func (c *nodeCache) put(id string, node *api.Node, modifyIndex uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.elems[id]; ok {
+		e.Value = &nodeCacheEntry{id: id, node: node, modifyIndex: modifyIndex}
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	e := c.ll.PushFront(&nodeCacheEntry{id: id, node: node, modifyIndex: modifyIndex})
+	c.elems[id] = e
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.elems, oldest.Value.(*nodeCacheEntry).id)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Package poller maintains an in-memory snapshot of Nomad cluster state,
+// kept fresh by long-lived blocking queries instead of being re-fetched on
+// every Prometheus scrape. Collectors read from the snapshot, so scrape
+// cost is O(snapshot size) and never blocks on the Nomad API.
+package poller
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const blockingWaitTime = 5 * time.Minute
+
+// Snapshot is an immutable point-in-time view of cluster state. A Poller
+// atomically swaps in a new Snapshot each time one of its watches completes
+// a successful poll, whether or not the blocking query saw a change;
+// callers should treat the value they load as read-only.
+type Snapshot struct {
+	Allocations  map[string]*api.Allocation
+	Nodes        map[string]*api.Node
+	Jobs         map[string]*api.Job
+	JobSummaries map[string]*api.JobSummary
+	Evaluations  map[string]*api.Evaluation
+	UpdatedAt    time.Time
+}
+
+func emptySnapshot() *Snapshot {
+	return &Snapshot{
+		Allocations:  map[string]*api.Allocation{},
+		Nodes:        map[string]*api.Node{},
+		Jobs:         map[string]*api.Job{},
+		JobSummaries: map[string]*api.JobSummary{},
+		Evaluations:  map[string]*api.Evaluation{},
+	}
+}
+
+// RequestCount is one (endpoint, code) tally of Nomad API calls the poller
+// has made, suitable for a nomad_api_requests_total{endpoint,code} counter.
+type RequestCount struct {
+	Endpoint string
+	Code     string
+	Count    float64
+}
+
+// Poller runs one blocking-query watch loop per resource type and serves
+// Snapshot() from whatever each loop most recently observed.
+type Poller struct {
+	client           *api.Client
+	allocConcurrency int
+
+	// tokenFn, when set, is called for every request to supply the current
+	// ACL SecretID (api.QueryOptions.AuthToken), so a rotated token takes
+	// effect without rebuilding the client.
+	tokenFn func() string
+	// namespace is applied to every request's api.QueryOptions.Namespace.
+	// "*" queries across all namespaces.
+	namespace string
+	// scheme is the URL scheme ("http" or "https") used to talk to the
+	// Nomad servers, reused to build per-node clients for alloc stats.
+	scheme string
+	// region and tlsConfig are copied from the main client's config onto
+	// every per-node client nodeClient builds, so alloc-stats requests
+	// against a node's client agent use the same region and, critically,
+	// the same CA/cert/key as the main client instead of an unauthenticated
+	// default config.
+	region    string
+	tlsConfig *api.TLSConfig
+
+	snapshot atomic.Value // *Snapshot
+	// updateMu serializes update()'s read-copy-mutate-store sequence across
+	// the watch loops sharing the composite Snapshot.
+	updateMu sync.Mutex
+
+	// nodeCache holds full api.Node records keyed by ID, reused across polls
+	// for nodes whose ModifyIndex hasn't changed so pollNodes doesn't re-fetch
+	// nodes that rarely change.
+	nodeCache *nodeCache
+
+	mu       sync.Mutex
+	requests map[[2]string]float64
+
+	statsMu       sync.Mutex
+	nodeClients   map[string]*api.Client
+	statsSnapshot atomic.Value // map[string]*AllocStats
+	statsErrors   map[string]float64
+
+	// oomMu guards oomCounters, the running per-task OOM-kill totals backing
+	// the monotonic allocation_oom_kills_total counter.
+	oomMu       sync.Mutex
+	oomCounters map[string]*oomCounter
+}
+
+// New builds a Poller around client. tokenFn may be nil when no ACL
+// authentication is configured. scheme is the URL scheme Nomad is reachable
+// on ("http" or "https"), used to build per-node clients for RunStats.
+// clientCfg is the api.Config client was built from; its Region and
+// TLSConfig are reused for per-node clients so alloc-stats requests to a
+// node's client agent authenticate the same way requests to the servers do.
+// Call Run to start its watch loops.
+func New(client *api.Client, tokenFn func() string, namespace string, scheme string, clientCfg *api.Config) *Poller {
+	p := &Poller{
+		client:           client,
+		allocConcurrency: 8,
+		tokenFn:          tokenFn,
+		namespace:        namespace,
+		scheme:           scheme,
+		region:           clientCfg.Region,
+		tlsConfig:        clientCfg.TLSConfig.Copy(),
+		nodeCache:        newNodeCache(defaultNodeCacheCapacity),
+		requests:         map[[2]string]float64{},
+		nodeClients:      map[string]*api.Client{},
+		statsErrors:      map[string]float64{},
+		oomCounters:      map[string]*oomCounter{},
+	}
+	p.snapshot.Store(emptySnapshot())
+	p.statsSnapshot.Store(map[string]*AllocStats{})
+	return p
+}
+
+// queryOptions builds the api.QueryOptions every poller request should use,
+// carrying the current ACL token and configured namespace.
+func (p *Poller) queryOptions(waitIndex uint64) *api.QueryOptions {
+	q := &api.QueryOptions{WaitIndex: waitIndex, WaitTime: blockingWaitTime, Namespace: p.namespace}
+	if p.tokenFn != nil {
+		q.AuthToken = p.tokenFn()
+	}
+	return q
+}
+
+// Snapshot returns the most recently observed cluster state. Safe to call
+// from any goroutine.
+func (p *Poller) Snapshot() *Snapshot {
+	return p.snapshot.Load().(*Snapshot)
+}
+
+// Stale reports whether the snapshot hasn't been refreshed within after.
+func (p *Poller) Stale(after time.Duration) bool {
+	return time.Since(p.Snapshot().UpdatedAt) > after
+}
+
+// RequestCounts returns a snapshot of the nomad_api_requests_total tallies
+// accumulated so far.
+func (p *Poller) RequestCounts() []RequestCount {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := make([]RequestCount, 0, len(p.requests))
+	for k, v := range p.requests {
+		counts = append(counts, RequestCount{Endpoint: k[0], Code: k[1], Count: v})
+	}
+	return counts
+}
+
+func (p *Poller) recordRequest(endpoint string, err error) {
+	code := "200"
+	if err != nil {
+		code = "error"
+	}
+	p.mu.Lock()
+	p.requests[[2]string{endpoint, code}]++
+	p.mu.Unlock()
+}
+
+// Run starts the per-resource watch loops in the background. It returns
+// immediately; the loops run for the lifetime of the process.
+func (p *Poller) Run() {
+	go p.watch("allocations", p.pollAllocations)
+	go p.watch("nodes", p.pollNodes)
+	go p.watch("jobs", p.pollJobs)
+	go p.watch("evaluations", p.pollEvaluations)
+}
+
+// watch repeatedly invokes poll with the last-seen Raft index, backing off
+// briefly on error so a flapping Nomad API doesn't spin the loop.
+func (p *Poller) watch(name string, poll func(waitIndex uint64) (uint64, error)) {
+	var waitIndex uint64
+	for {
+		next, err := poll(waitIndex)
+		if err != nil {
+			log.Println("poller:", name, "watch error:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		waitIndex = next
+	}
+}
+
+func (p *Poller) pollAllocations(waitIndex uint64) (uint64, error) {
+	stubs, qm, err := p.client.Allocations().List(p.queryOptions(waitIndex))
+	p.recordRequest("allocations/list", err)
+	if err != nil {
+		return waitIndex, err
+	}
+
+	allocs := p.fetchAllocations(runningStubs(stubs))
+	p.update(func(s *Snapshot) { s.Allocations = allocs })
+	return qm.LastIndex, nil
+}
+
+func runningStubs(stubs []*api.AllocationListStub) []*api.AllocationListStub {
+	var running []*api.AllocationListStub
+	for _, s := range stubs {
+		if s.ClientStatus == "running" {
+			running = append(running, s)
+		}
+	}
+	return running
+}
+
+// fetchAllocations resolves full Allocation objects for the given stubs
+// with a bounded worker pool, so a large running-allocation count doesn't
+// open unbounded concurrent connections to Nomad.
+func (p *Poller) fetchAllocations(stubs []*api.AllocationListStub) map[string]*api.Allocation {
+	allocs := make(map[string]*api.Allocation, len(stubs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.allocConcurrency)
+
+	for _, s := range stubs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			alloc, _, err := p.client.Allocations().Info(id, p.queryOptions(0))
+			p.recordRequest("allocations/info", err)
+			if err != nil {
+				log.Println("poller: fetching allocation", id, "failed:", err)
+				return
+			}
+			mu.Lock()
+			allocs[id] = alloc
+			mu.Unlock()
+		}(s.ID)
+	}
+	wg.Wait()
+	return allocs
+}
+
+func (p *Poller) pollNodes(waitIndex uint64) (uint64, error) {
+	stubs, qm, err := p.client.Nodes().List(p.queryOptions(waitIndex))
+	p.recordRequest("nodes/list", err)
+	if err != nil {
+		return waitIndex, err
+	}
+
+	nodes := make(map[string]*api.Node, len(stubs))
+	for _, s := range stubs {
+		if cached, ok := p.nodeCache.get(s.ID, s.ModifyIndex); ok {
+			nodes[s.ID] = cached
+			continue
+		}
+
+		node, _, err := p.client.Nodes().Info(s.ID, p.queryOptions(0))
+		p.recordRequest("nodes/info", err)
+		if err != nil {
+			log.Println("poller: fetching node", s.ID, "failed:", err)
+			continue
+		}
+		p.nodeCache.put(s.ID, node, s.ModifyIndex)
+		nodes[s.ID] = node
+	}
+	p.update(func(s *Snapshot) { s.Nodes = nodes })
+	return qm.LastIndex, nil
+}
+
+func (p *Poller) pollJobs(waitIndex uint64) (uint64, error) {
+	stubs, qm, err := p.client.Jobs().List(p.queryOptions(waitIndex))
+	p.recordRequest("jobs/list", err)
+	if err != nil {
+		return waitIndex, err
+	}
+
+	jobs := make(map[string]*api.Job, len(stubs))
+	summaries := make(map[string]*api.JobSummary, len(stubs))
+	for _, s := range stubs {
+		job, _, err := p.client.Jobs().Info(s.ID, p.queryOptions(0))
+		p.recordRequest("jobs/info", err)
+		if err != nil {
+			log.Println("poller: fetching job", s.ID, "failed:", err)
+			continue
+		}
+		jobs[s.ID] = job
+
+		summary, _, err := p.client.Jobs().Summary(s.ID, p.queryOptions(0))
+		p.recordRequest("jobs/summary", err)
+		if err != nil {
+			log.Println("poller: fetching job summary", s.ID, "failed:", err)
+			continue
+		}
+		summaries[s.ID] = summary
+	}
+	p.update(func(s *Snapshot) {
+		s.Jobs = jobs
+		s.JobSummaries = summaries
+	})
+	return qm.LastIndex, nil
+}
+
+func (p *Poller) pollEvaluations(waitIndex uint64) (uint64, error) {
+	stubs, qm, err := p.client.Evaluations().List(p.queryOptions(waitIndex))
+	p.recordRequest("evaluations/list", err)
+	if err != nil {
+		return waitIndex, err
+	}
+
+	evals := make(map[string]*api.Evaluation, len(stubs))
+	for _, s := range stubs {
+		evals[s.ID] = &api.Evaluation{
+			ID:     s.ID,
+			JobID:  s.JobID,
+			Status: s.Status,
+		}
+	}
+	p.update(func(s *Snapshot) { s.Evaluations = evals })
+	return qm.LastIndex, nil
+}
+
+// update replaces the current snapshot with a shallow copy that has had
+// mutate applied, and stamps UpdatedAt. Each resource's map is wholesale
+// replaced by its own watch loop, but the read-copy-mutate-store sequence
+// itself must be serialized: two loops racing to update() from the same
+// stale snapshot would otherwise have the second store clobber the first
+// loop's field back to its pre-update value. updateMu makes the sequence
+// atomic as a whole instead of relying on snapshot's own atomicity.
+func (p *Poller) update(mutate func(*Snapshot)) {
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+
+	current := p.Snapshot()
+	next := &Snapshot{
+		Allocations:  current.Allocations,
+		Nodes:        current.Nodes,
+		Jobs:         current.Jobs,
+		JobSummaries: current.JobSummaries,
+		Evaluations:  current.Evaluations,
+	}
+	mutate(next)
+	next.UpdatedAt = time.Now()
+	p.snapshot.Store(next)
+}
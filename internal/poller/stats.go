@@ -0,0 +1,230 @@
+package poller
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// AllocStats is the resource usage the stats poller most recently observed
+// for one allocation, broken down per task.
+type AllocStats struct {
+	Tasks map[string]*TaskStats
+}
+
+// TaskStats is one task's instantaneous resource usage, as reported by the
+// client agent running it.
+type TaskStats struct {
+	CPUTicks    float64
+	MemoryRSS   uint64
+	MemoryCache uint64
+	MemorySwap  uint64
+	Restarts    int
+	OOMKills    int
+}
+
+// oomCounter tracks a monotonically increasing OOM-kill total for one task,
+// derived from a bounded event log whose visible count can decrease as old
+// events age out.
+type oomCounter struct {
+	lastSeen int // OOM events visible in the log as of the last poll
+	total    int // running total, never decreases
+}
+
+// RunStats starts a background loop that polls client.Allocations().Stats
+// for every running allocation once per interval, using up to concurrency
+// requests at a time. Unlike the blocking-query watches started by Run,
+// this is a plain ticker: Stats has no index to block on.
+func (p *Poller) RunStats(interval time.Duration, concurrency int) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			p.pollAllocStats(concurrency)
+			<-t.C
+		}
+	}()
+}
+
+// StatsSnapshot returns the most recently observed per-allocation stats,
+// keyed by allocation ID.
+func (p *Poller) StatsSnapshot() map[string]*AllocStats {
+	return p.statsSnapshot.Load().(map[string]*AllocStats)
+}
+
+// StatsErrorCounts returns the nomad_allocation_stats_scrape_errors_total
+// tally accumulated so far, keyed by node name.
+func (p *Poller) StatsErrorCounts() map[string]float64 {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	counts := make(map[string]float64, len(p.statsErrors))
+	for k, v := range p.statsErrors {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (p *Poller) pollAllocStats(concurrency int) {
+	snapshot := p.Snapshot()
+
+	stats := make(map[string]*AllocStats, len(snapshot.Allocations))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for id, alloc := range snapshot.Allocations {
+		node := snapshot.Nodes[alloc.NodeID]
+		if node == nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string, alloc *api.Allocation, node *api.Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s, err := p.fetchAllocStats(alloc, node)
+			if err != nil {
+				log.Println("poller: fetching alloc stats for", id, "on node", node.Name, "failed:", err)
+				p.recordStatsError(node.Name)
+				return
+			}
+			mu.Lock()
+			stats[id] = s
+			mu.Unlock()
+		}(id, alloc, node)
+	}
+	wg.Wait()
+
+	p.pruneOOMCounters(stats)
+	p.statsSnapshot.Store(stats)
+}
+
+// pruneOOMCounters drops oomCounters entries for alloc/task pairs that no
+// longer appear in the current stats round, so the map doesn't grow
+// unbounded as allocations come and go.
+func (p *Poller) pruneOOMCounters(stats map[string]*AllocStats) {
+	live := make(map[string]bool, len(stats))
+	for allocID, s := range stats {
+		for task := range s.Tasks {
+			live[oomCounterKey(allocID, task)] = true
+		}
+	}
+	p.oomMu.Lock()
+	defer p.oomMu.Unlock()
+	for key := range p.oomCounters {
+		if !live[key] {
+			delete(p.oomCounters, key)
+		}
+	}
+}
+
+func (p *Poller) recordStatsError(node string) {
+	p.statsMu.Lock()
+	p.statsErrors[node]++
+	p.statsMu.Unlock()
+}
+
+// fetchAllocStats resolves usage for alloc directly from the client agent
+// running it, rather than fanning the request through a Nomad server.
+func (p *Poller) fetchAllocStats(alloc *api.Allocation, node *api.Node) (*AllocStats, error) {
+	client, err := p.nodeClient(node)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := client.Allocations().Stats(alloc, p.queryOptions(0))
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make(map[string]*TaskStats, len(usage.Tasks))
+	for name, task := range usage.Tasks {
+		ts := &TaskStats{}
+		if task.ResourceUsage != nil {
+			if cpu := task.ResourceUsage.CpuStats; cpu != nil {
+				ts.CPUTicks = cpu.TotalTicks
+			}
+			if mem := task.ResourceUsage.MemoryStats; mem != nil {
+				ts.MemoryRSS = mem.RSS
+				ts.MemoryCache = mem.Cache
+				ts.MemorySwap = mem.Swap
+			}
+		}
+		if state, ok := alloc.TaskStates[name]; ok {
+			ts.Restarts = int(state.Restarts)
+			ts.OOMKills = p.oomKillTotal(alloc.ID, name, countOOMKills(state))
+		}
+		tasks[name] = ts
+	}
+
+	return &AllocStats{Tasks: tasks}, nil
+}
+
+// countOOMKills counts the OOM-kill events currently visible in a task's
+// event log, since the Nomad API has no dedicated counter for it. Nomad
+// truncates this log to a bounded recent window, so the count this returns
+// can decrease as old events age out; callers must accumulate it through
+// oomKillTotal rather than exposing it directly as a counter.
+func countOOMKills(state *api.TaskState) int {
+	var n int
+	for _, ev := range state.Events {
+		if strings.Contains(strings.ToLower(ev.Type), "oom") {
+			n++
+		}
+	}
+	return n
+}
+
+func oomCounterKey(allocID, task string) string {
+	return allocID + "/" + task
+}
+
+// oomKillTotal folds the current, possibly-truncated event-log OOM count
+// into a running total that only ever increases, so
+// allocation_oom_kills_total stays a valid Prometheus counter even once
+// older OOM events age out of a task's bounded event log.
+func (p *Poller) oomKillTotal(allocID, task string, seen int) int {
+	key := oomCounterKey(allocID, task)
+
+	p.oomMu.Lock()
+	defer p.oomMu.Unlock()
+
+	c, ok := p.oomCounters[key]
+	if !ok {
+		c = &oomCounter{}
+		p.oomCounters[key] = c
+	}
+	if seen > c.lastSeen {
+		c.total += seen - c.lastSeen
+	}
+	c.lastSeen = seen
+	return c.total
+}
+
+// nodeClient returns (creating and caching if needed) an API client that
+// talks directly to node's client agent, so Stats requests don't fan
+// through a Nomad server. It carries the same region and TLS configuration
+// as the main client, so it works against client agents behind mTLS the
+// same way the main client works against the servers.
+func (p *Poller) nodeClient(node *api.Node) (*api.Client, error) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	if c, ok := p.nodeClients[node.ID]; ok {
+		return c, nil
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.Address = p.scheme + "://" + node.HTTPAddr
+	cfg.Region = p.region
+	cfg.TLSConfig = p.tlsConfig.Copy()
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.nodeClients[node.ID] = client
+	return client, nil
+}
@@ -0,0 +1,175 @@
+// Package tlsconfig builds a *tls.Config for the exporter's own web server
+// and keeps it in sync with the server certificate and client CA bundles on
+// disk, so operators can rotate them without restarting the process.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Config describes the files backing the exporter's TLS listener. KeyFile is
+// required whenever CertFile is set; ClientCAFiles is optional and, when
+// non-empty, enables mutual TLS by requiring and verifying client certs.
+type Config struct {
+	CertFile      string
+	KeyFile       string
+	ClientCAFiles []string
+
+	// ReloadInterval controls how often the files are stat'd for changes.
+	// Defaults to 30s when zero.
+	ReloadInterval time.Duration
+}
+
+// Manager holds a hot-reloadable *tls.Config built from Config. It re-reads
+// the certificate and CA bundle files on disk periodically and atomically
+// swaps them in, so in-flight connections are unaffected and new ones pick
+// up rotated material without a restart.
+type Manager struct {
+	cfg     Config
+	current atomic.Value // holds *tls.Config
+
+	certModTime time.Time
+	caModTimes  []time.Time
+}
+
+// NewManager loads the initial certificate and CA bundles and starts a
+// background goroutine that reloads them every cfg.ReloadInterval.
+func NewManager(cfg Config) (*Manager, error) {
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = 30 * time.Second
+	}
+	m := &Manager{cfg: cfg}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	go m.watch()
+	return m, nil
+}
+
+// TLSConfig returns a *tls.Config whose GetCertificate and
+// GetConfigForClient callbacks always resolve against the most recently
+// loaded material.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate:     func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return m.certificate(), nil },
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return m.clientConfig(), nil },
+	}
+}
+
+func (m *Manager) certificate() *tls.Certificate {
+	return m.current.Load().(*state).cert
+}
+
+func (m *Manager) clientConfig() *tls.Config {
+	s := m.current.Load().(*state)
+	return &tls.Config{
+		Certificates: []tls.Certificate{*s.cert},
+		ClientCAs:    s.clientCAs,
+		ClientAuth:   s.clientAuth,
+	}
+}
+
+type state struct {
+	cert       *tls.Certificate
+	clientCAs  *x509.CertPool
+	clientAuth tls.ClientAuthType
+}
+
+func (m *Manager) watch() {
+	t := time.NewTicker(m.cfg.ReloadInterval)
+	defer t.Stop()
+	for range t.C {
+		if err := m.reloadIfChanged(); err != nil {
+			log.Println("tlsconfig: reload failed, keeping previous config:", err)
+		}
+	}
+}
+
+func (m *Manager) reloadIfChanged() error {
+	changed, err := m.changed()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	log.Println("tlsconfig: certificate or CA bundle changed on disk, reloading")
+	return m.reload()
+}
+
+func (m *Manager) changed() (bool, error) {
+	certMod, err := modTime(m.cfg.CertFile)
+	if err != nil {
+		return false, err
+	}
+	if !certMod.Equal(m.certModTime) {
+		return true, nil
+	}
+	for i, f := range m.cfg.ClientCAFiles {
+		mod, err := modTime(f)
+		if err != nil {
+			return false, err
+		}
+		if i >= len(m.caModTimes) || !mod.Equal(m.caModTimes[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func modTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+func (m *Manager) reload() error {
+	cert, err := tls.LoadX509KeyPair(m.cfg.CertFile, m.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tlsconfig: loading server cert/key: %w", err)
+	}
+	certMod, err := modTime(m.cfg.CertFile)
+	if err != nil {
+		return err
+	}
+
+	clientAuth := tls.NoClientCert
+	var pool *x509.CertPool
+	caMods := make([]time.Time, len(m.cfg.ClientCAFiles))
+	if len(m.cfg.ClientCAFiles) > 0 {
+		pool = x509.NewCertPool()
+		for i, f := range m.cfg.ClientCAFiles {
+			pem, err := ioutil.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("tlsconfig: reading client CA file %s: %w", f, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("tlsconfig: no certificates found in client CA file %s", f)
+			}
+			mod, err := modTime(f)
+			if err != nil {
+				return err
+			}
+			caMods[i] = mod
+		}
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	m.current.Store(&state{
+		cert:       &cert,
+		clientCAs:  pool,
+		clientAuth: clientAuth,
+	})
+	m.certModTime = certMod
+	m.caModTimes = caMods
+	return nil
+}
@@ -0,0 +1,84 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"testing"
+	"time"
+)
+
+// writeTestCert materializes the given PEM-encoded cert/key pair on disk and
+// returns their paths.
+func writeTestCert(t *testing.T, dir, certPEM, keyPEM string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = dir + "/cert.pem"
+	keyFile = dir + "/key.pem"
+	if err := os.WriteFile(certFile, []byte(certPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, []byte(keyPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestManagerReloadsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, certPEM1, keyPEM1)
+
+	m, err := NewManager(Config{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	first := m.certificate()
+	if first == nil {
+		t.Fatal("expected an initial certificate to be loaded")
+	}
+
+	// Swap in a different cert/key pair and bump mtimes so the poller notices.
+	later := time.Now().Add(time.Hour)
+	writeTestCert(t, dir, certPEM2, keyPEM2)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if m.certificate() != first {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("certificate was not reloaded after the file changed on disk")
+}
+
+func TestManagerEnablesClientAuthWhenCAConfigured(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, certPEM1, keyPEM1)
+	caFile := dir + "/ca.pem"
+	if err := os.WriteFile(caFile, []byte(certPEM1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewManager(Config{
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+		ClientCAFiles: []string{caFile},
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	got := m.clientConfig()
+	if got.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("expected RequireAndVerifyClientCert, got %v", got.ClientAuth)
+	}
+	if got.ClientCAs == nil {
+		t.Fatal("expected a non-nil client CA pool")
+	}
+}
@@ -13,7 +13,12 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/version"
+
+	"github.com/pere3/nomad-exporter/internal/nomadauth"
+	"github.com/pere3/nomad-exporter/internal/poller"
+	"github.com/pere3/nomad-exporter/internal/tlsconfig"
 )
 
 const (
@@ -26,100 +31,110 @@ var (
 		"Was the last query of Nomad successful.",
 		nil, nil,
 	)
-	allocationMemoryLimit = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "allocation_memory_limit"),
-		"Allocation memory limit",
-		[]string{"job", "group", "alloc", "alloc_id", "region", "datacenter", "node"}, nil,
-	)
 )
 
-func AllocationsByStatus(allocs []*api.AllocationListStub, status string) []*api.AllocationListStub {
-	var resp []*api.AllocationListStub
-	for _, a := range allocs {
-		if a.ClientStatus == status {
-			resp = append(resp, a)
-		}
-	}
-	return resp
-}
-
+// Exporter is a prometheus.Collector that fans out to a registry of
+// per-resource sub-collectors (jobCollector, nodeCollector, ...), each
+// selectable via --collector.<name>. Collectors that watch
+// high-cardinality, frequently-changing resources read from a background
+// poller.Poller instead of calling Nomad directly on every scrape.
 type Exporter struct {
-	client *api.Client
+	client     *api.Client
+	poller     *poller.Poller
+	staleAfter time.Duration
+	collectors map[string]collector
+	tokenMgr   *nomadauth.Manager
+	namespace  string
 }
 
-func NewExporter(cfg *api.Config) (*Exporter, error) {
+// NewExporter builds an Exporter for a single Nomad cluster. auth describes
+// how to obtain an ACL SecretID; auth.Client is set automatically from the
+// client built for cfg. nomadNamespace is applied to every Nomad query ("*"
+// queries across all namespaces).
+func NewExporter(cfg *api.Config, enabled map[string]bool, staleAfter time.Duration, auth nomadauth.Config, nomadNamespace string, statsCfg allocStatsConfig) (*Exporter, error) {
 	client, err := api.NewClient(cfg)
 	if err != nil {
 		return nil, err
 	}
+
+	scheme := "http"
+	if strings.HasPrefix(cfg.Address, "https://") {
+		scheme = "https"
+	}
+
+	collectors := map[string]collector{}
+	for name, c := range defaultCollectors() {
+		if enabled[name] {
+			collectors[name] = c
+		}
+	}
+
+	auth.Client = client
+	tokenMgr, err := nomadauth.NewManager(auth)
+	if err != nil {
+		return nil, fmt.Errorf("configuring Nomad ACL token: %w", err)
+	}
+
+	p := poller.New(client, tokenMgr.SecretID, nomadNamespace, scheme, cfg)
+	p.Run()
+	if enabled["alloc-stats"] {
+		p.RunStats(statsCfg.interval, statsCfg.concurrency)
+	}
+
 	return &Exporter{
-		client: client,
+		client:     client,
+		poller:     p,
+		staleAfter: staleAfter,
+		collectors: collectors,
+		tokenMgr:   tokenMgr,
+		namespace:  nomadNamespace,
 	}, nil
 }
 
 // Describe implements Collector interface.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- up
-	ch <- allocationMemoryLimit
+	ch <- scrapeCollectorDuration
+	ch <- scrapeCollectorSuccess
+	for _, c := range e.collectors {
+		c.describe(ch)
+	}
 }
 
 // Collect collects nomad metrics
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	ch <- prometheus.MustNewConstMetric(
-		up, prometheus.GaugeValue, 1,
-	)
-	allocs, _, err := e.client.Allocations().List(&api.QueryOptions{})
-	if err != nil {
-		logError(err)
-		return
+	upValue := 1.0
+	if e.poller.Stale(e.staleAfter) {
+		upValue = 0
 	}
+	ch <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, upValue)
 
-	runningAllocs := AllocationsByStatus(allocs, "running")
+	ds := &dataSource{
+		client:    e.client,
+		poller:    e.poller,
+		snapshot:  e.poller.Snapshot(),
+		token:     e.tokenMgr.SecretID(),
+		namespace: e.namespace,
+	}
 
 	var w sync.WaitGroup
-	for _, a := range runningAllocs {
+	for _, c := range e.collectors {
 		w.Add(1)
-		go func(a *api.AllocationListStub) {
+		go func(c collector) {
 			defer w.Done()
-			alloc, _, err := e.client.Allocations().Info(a.ID, &api.QueryOptions{})
-			if err != nil {
-				logError(err)
-				return
-			}
-			
-			node, _, err := e.client.Nodes().Info(alloc.NodeID, &api.QueryOptions{})
-			if err != nil {
-				logError(err)
-				return
-			}
-			ch <- prometheus.MustNewConstMetric(
-				allocationMemoryLimit, prometheus.GaugeValue, float64(alloc.Resources.MemoryMB), alloc.Job.Name, alloc.TaskGroup, alloc.Name, alloc.ID, alloc.Job.Region, node.Datacenter, node.Name,
-			)
-		}(a)
+			runCollector(c, ds, ch)
+		}(c)
 	}
 	w.Wait()
 }
 
-func getRunningAllocs(client *api.Client, nodeID string) ([]*api.Allocation, error) {
-	var allocs []*api.Allocation
-
-	// Query the node allocations
-	nodeAllocs, _, err := client.Nodes().Allocations(nodeID, nil)
-	// Filter list to only running allocations
-	for _, alloc := range nodeAllocs {
-		if alloc.ClientStatus == "running" {
-			allocs = append(allocs, alloc)
-		}
-	}
-	return allocs, err
-}
-
 func main() {
 	var (
 		showVersion   = flag.Bool("version", false, "Print version information.")
 		listenAddress = flag.String("web.listen-address", ":9172", "Address to listen on for web interface and telemetry.")
 		metricsPath   = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
-		nomadServer   = flag.String("nomad.server", "http://localhost:4646", "HTTP API address of a Nomad server or agent.")
+		nomadServers  nomadServerFlags
+		configFile    = flag.String("config.file", "", "Path to a YAML file listing multiple Nomad clusters to scrape. Overrides --nomad.server.")
 		nomadTimeout  = flag.String("nomad.timeout", "30", "HTTP timeout to contact Nomad agent.")
 		tlsCaFile     = flag.String("tls.ca-file", "", "ca-file path to a PEM-encoded CA cert file to use to verify the connection to nomad server")
 		tlsCaPath     = flag.String("tls.ca-path", "", "ca-path is the path to a directory of PEM-encoded CA cert files to verify the connection to nomad server")
@@ -127,39 +142,126 @@ func main() {
 		tlsKey        = flag.String("tls.key-file", "", "key-file is the path to the key for cert-file")
 		tlsInsecure   = flag.Bool("tls.insecure", false, "insecure enables or disables SSL verification")
 		tlsServerName = flag.String("tls.tls-server-name", "", "tls-server-name sets the SNI for Nomad ssl connection")
+
+		webTLSCertFile     = flag.String("web.tls-cert-file", "", "Path to a PEM-encoded certificate for the exporter's own HTTP server. Enables HTTPS when set.")
+		webTLSKeyFile      = flag.String("web.tls-key-file", "", "Path to the PEM-encoded private key matching --web.tls-cert-file.")
+		webTLSClientCAFile webTLSClientCAFiles
+		webIPRange         = flag.String("web.ip-range", "", "Comma-separated list of CIDR ranges allowed to reach the web interface and metrics endpoint. Empty allows all.")
+		webBasicAuthUsers  = flag.String("web.basic-auth-users", "", "Path to a htpasswd-style file (user:bcrypt-hash per line) required to access the metrics endpoint.")
+
+		cacheStaleAfter = flag.Duration("cache.stale-after", 2*time.Minute, "Mark nomad_up=0 if the background poller's snapshot is older than this.")
+
+		nomadToken      = flag.String("nomad.token", "", "Nomad ACL SecretID to use. Defaults to the NOMAD_SECRET_ID environment variable.")
+		nomadTokenFile  = flag.String("nomad.token-file", "", "Path to a file containing a Nomad ACL SecretID (or, with --nomad.workload-identity-auth-method, a workload identity JWT), re-read on change. Defaults to the NOMAD_TOKEN_FILE environment variable. Overrides --nomad.token.")
+		nomadAuthMethod = flag.String("nomad.workload-identity-auth-method", "", "Nomad ACL auth method name to exchange the --nomad.token-file JWT for a SecretID via workload identity. Requires --nomad.token-file.")
+		nomadNamespace  = flag.String("nomad.namespace", "default", "Nomad namespace to query. Use \"*\" to query across all namespaces.")
+
+		allocStatsInterval    = flag.Duration("collector.alloc-stats.interval", time.Minute, "How often the alloc-stats collector polls allocation resource usage. Only applies when --collector.alloc-stats is enabled.")
+		allocStatsConcurrency = flag.Int("collector.alloc-stats.concurrency", 8, "Maximum concurrent Stats requests the alloc-stats collector sends to client agents.")
 	)
+	flag.Var(&webTLSClientCAFile, "web.tls-client-ca-file", "Path to a PEM-encoded client CA bundle to require and verify client certificates. May be repeated.")
+	flag.Var(&nomadServers, "nomad.server", "HTTP API address of a Nomad server or agent. May be repeated to scrape multiple clusters.")
+
+	collectorFlags := map[string]*bool{}
+	for name := range defaultCollectors() {
+		collectorFlags[name] = flag.Bool("collector."+name, defaultCollectorEnabled(name), "Enable the "+name+" collector.")
+	}
 	flag.Parse()
 
 	if *showVersion {
 		fmt.Fprintln(os.Stdout, version.Print("nomad_exporter"))
 		os.Exit(0)
 	}
-	cfg := api.DefaultConfig()
-	cfg.Address = *nomadServer
-
-	if strings.HasPrefix(cfg.Address, "https://") {
-		cfg.TLSConfig.CACert = *tlsCaFile
-		cfg.TLSConfig.CAPath = *tlsCaPath
-		cfg.TLSConfig.ClientKey = *tlsKey
-		cfg.TLSConfig.ClientCert = *tlsCert
-		cfg.TLSConfig.Insecure = *tlsInsecure
-		cfg.TLSConfig.TLSServerName = *tlsServerName
+	var clusters []clusterConfig
+	if *configFile != "" {
+		var err error
+		clusters, err = loadClustersFile(*configFile)
+		if err != nil {
+			log.Fatal("failed to load --config.file: ", err)
+		}
+	} else {
+		if len(nomadServers) == 0 {
+			nomadServers = nomadServerFlags{"http://localhost:4646"}
+		}
+		clusters = clustersFromServers(nomadServers)
+	}
+	if err := validateClusterNames(clusters); err != nil {
+		log.Fatal("invalid cluster configuration: ", err)
 	}
 
 	timeout, err := strconv.Atoi(*nomadTimeout)
 	if err != nil {
 		log.Fatal(err)
 	}
-	cfg.WaitTime = time.Duration(timeout) * time.Second
 
-	exporter, err := NewExporter(cfg)
+	enabledCollectors := map[string]bool{}
+	for name, enabled := range collectorFlags {
+		enabledCollectors[name] = *enabled
+	}
+
+	defaultToken, defaultTokenFile := nomadauth.ResolveDefaults(*nomadToken, *nomadTokenFile)
+	statsCfg := allocStatsConfig{interval: *allocStatsInterval, concurrency: *allocStatsConcurrency}
+
+	registry := prometheus.NewRegistry()
+	exporters := map[string]*Exporter{}
+	for _, cl := range clusters {
+		cfg := api.DefaultConfig()
+		cfg.Address = cl.Address
+		cfg.Region = cl.Region
+		cfg.SecretID = firstNonEmpty(cl.Token, defaultToken)
+		cfg.WaitTime = time.Duration(timeout) * time.Second
+
+		if strings.HasPrefix(cfg.Address, "https://") {
+			cfg.TLSConfig.CACert = firstNonEmpty(cl.TLS.CAFile, *tlsCaFile)
+			cfg.TLSConfig.CAPath = firstNonEmpty(cl.TLS.CAPath, *tlsCaPath)
+			cfg.TLSConfig.ClientCert = firstNonEmpty(cl.TLS.CertFile, *tlsCert)
+			cfg.TLSConfig.ClientKey = firstNonEmpty(cl.TLS.KeyFile, *tlsKey)
+			cfg.TLSConfig.TLSServerName = firstNonEmpty(cl.TLS.ServerName, *tlsServerName)
+			cfg.TLSConfig.Insecure = cl.TLS.Insecure || *tlsInsecure
+		}
+
+		auth := nomadauth.Config{
+			StaticToken: cfg.SecretID,
+			TokenFile:   firstNonEmpty(cl.TokenFile, defaultTokenFile),
+			AuthMethod:  firstNonEmpty(cl.AuthMethod, *nomadAuthMethod),
+		}
+		if auth.TokenFile != "" {
+			// A token file (static or workload identity) takes precedence
+			// over a bare --nomad.token/token YAML value.
+			auth.StaticToken = ""
+		}
+
+		exporter, err := NewExporter(cfg, enabledCollectors, *cacheStaleAfter, auth, firstNonEmpty(cl.Namespace, *nomadNamespace), statsCfg)
+		if err != nil {
+			log.Fatal("failed to build exporter for cluster ", cl.Name, ": ", err)
+		}
+		exporters[cl.Name] = exporter
+
+		clusterRegistry := prometheus.WrapRegistererWith(prometheus.Labels{"cluster": cl.Name}, registry)
+		clusterRegistry.MustRegister(exporter)
+	}
+
+	ipRanges, err := parseIPRanges(*webIPRange)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("invalid --web.ip-range: ", err)
+	}
+	var basicAuthUsers basicAuthUsers
+	if *webBasicAuthUsers != "" {
+		basicAuthUsers, err = loadHtpasswdFile(*webBasicAuthUsers)
+		if err != nil {
+			log.Fatal("invalid --web.basic-auth-users: ", err)
+		}
+	}
+	protect := func(h http.Handler) http.Handler {
+		return ipRangeFilter(ipRanges, basicAuthFilter(basicAuthUsers, h))
 	}
-	prometheus.MustRegister(exporter)
 
-	http.Handle(*metricsPath, prometheus.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, protect(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	mux.Handle("/probe", protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probeCluster(w, r, exporters)
+	})))
+	mux.Handle("/", protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>Nomad Exporter</title></head>
              <body>
@@ -167,13 +269,24 @@ func main() {
              <p><a href='` + *metricsPath + `'>Metrics</a></p>
              </body>
              </html>`))
-	})
+	})))
 
-	log.Println("Listening on", *listenAddress)
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
-}
+	server := &http.Server{Addr: *listenAddress, Handler: mux}
 
-func logError(err error) {
-	log.Println("Query error", err)
-	return
+	if *webTLSCertFile != "" {
+		mgr, err := tlsconfig.NewManager(tlsconfig.Config{
+			CertFile:      *webTLSCertFile,
+			KeyFile:       *webTLSKeyFile,
+			ClientCAFiles: webTLSClientCAFile,
+		})
+		if err != nil {
+			log.Fatal("failed to load web TLS configuration: ", err)
+		}
+		server.TLSConfig = mgr.TLSConfig()
+		log.Println("Listening on", *listenAddress, "with TLS")
+		log.Fatal(server.ListenAndServeTLS("", ""))
+	}
+
+	log.Println("Listening on", *listenAddress)
+	log.Fatal(server.ListenAndServe())
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeCluster implements the Blackbox-exporter-style /probe?target=<cluster>
+// endpoint: it scrapes a single configured cluster on demand instead of
+// every cluster registered with the exporter.
+func probeCluster(w http.ResponseWriter, r *http.Request, exporters map[string]*Exporter) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	exporter, ok := exporters[target]
+	if !ok {
+		http.Error(w, "unknown target: "+target, http.StatusNotFound)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(prometheus.Labels{"cluster": target}, registry).MustRegister(exporter)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// webTLSClientCAFiles implements flag.Value so --web.tls-client-ca-file can
+// be repeated to supply more than one trusted client CA bundle.
+type webTLSClientCAFiles []string
+
+func (f *webTLSClientCAFiles) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *webTLSClientCAFiles) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// ipRangeFilter restricts handler to requests originating from one of the
+// given CIDR ranges. A nil or empty ranges list disables filtering.
+func ipRangeFilter(ranges []*net.IPNet, handler http.Handler) http.Handler {
+	if len(ranges) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			http.Error(w, "could not determine client IP", http.StatusForbidden)
+			return
+		}
+		for _, n := range ranges {
+			if n.Contains(ip) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "client IP not allowed", http.StatusForbidden)
+	})
+}
+
+// parseIPRanges parses a comma-separated list of CIDR ranges, e.g.
+// "10.0.0.0/8,192.168.1.0/24".
+func parseIPRanges(csv string) ([]*net.IPNet, error) {
+	if csv == "" {
+		return nil, nil
+	}
+	var ranges []*net.IPNet
+	for _, part := range strings.Split(csv, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, n)
+	}
+	return ranges, nil
+}
+
+// basicAuthUsers maps username to a bcrypt password hash, htpasswd-style.
+type basicAuthUsers map[string]string
+
+// loadHtpasswdFile reads a htpasswd file containing "user:bcrypt-hash" lines.
+func loadHtpasswdFile(path string) (basicAuthUsers, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := basicAuthUsers{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		users[parts[0]] = parts[1]
+	}
+	return users, scanner.Err()
+}
+
+// basicAuthFilter requires HTTP basic auth against users when non-empty.
+func basicAuthFilter(users basicAuthUsers, handler http.Handler) http.Handler {
+	if len(users) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			unauthorized(w)
+			return
+		}
+		hash, known := users[username]
+		if !known {
+			unauthorized(w)
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			unauthorized(w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="nomad-exporter"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}